@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// manifestInfo is the JSON representation of a manifest returned by the
+// API, layering metadata (size, creation time, config labels) on top of
+// the digest/layers resolved for vulnerability scanning.
+type manifestInfo struct {
+	Digest  string            `json:"digest"`
+	Size    int64             `json:"size"`
+	Created string            `json:"created,omitempty"`
+	Layers  []string          `json:"layers"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// imageConfig is the subset of a Docker image config blob the API exposes.
+type imageConfig struct {
+	Created string `json:"created"`
+	Config  struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+// Manifest resolves repository:reference into the metadata the JSON API
+// reports: digest, total size, creation time, layer digests and config
+// labels.
+func (r *Registry) Manifest(repository, reference string) (manifestInfo, error) {
+	digest, layers, err := r.manifestLayers(repository, reference)
+	if err != nil {
+		return manifestInfo{}, err
+	}
+
+	req, err := http.NewRequest("GET", r.URI+"/v2/"+repository+"/manifests/"+reference, nil)
+	if err != nil {
+		return manifestInfo{}, fmt.Errorf("building manifest request for %s:%s: %v", repository, reference, err)
+	}
+	req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return manifestInfo{}, fmt.Errorf("fetching manifest %s:%s: %v", repository, reference, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return manifestInfo{}, fmt.Errorf("fetching manifest %s:%s: registry returned %s", repository, reference, res.Status)
+	}
+
+	var m struct {
+		Config struct {
+			Digest string `json:"digest"`
+			Size   int64  `json:"size"`
+		} `json:"config"`
+		Layers []struct {
+			Size int64 `json:"size"`
+		} `json:"layers"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+		return manifestInfo{}, fmt.Errorf("decoding manifest %s:%s: %v", repository, reference, err)
+	}
+
+	info := manifestInfo{Digest: digest, Layers: layers, Size: m.Config.Size}
+	for _, l := range m.Layers {
+		info.Size += l.Size
+	}
+
+	if m.Config.Digest != "" {
+		cfg, err := r.blobConfig(repository, m.Config.Digest)
+		if err != nil {
+			log.Printf("fetching image config %s:%s: %v", repository, m.Config.Digest, err)
+		} else {
+			info.Created = cfg.Created
+			info.Labels = cfg.Config.Labels
+		}
+	}
+
+	return info, nil
+}
+
+// blobConfig fetches and decodes the image config blob at digest.
+func (r *Registry) blobConfig(repository, digest string) (imageConfig, error) {
+	res, b, err := r.doRequestE("GET", r.URI+"/v2/"+repository+"/blobs/"+digest)
+	if err != nil {
+		return imageConfig{}, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return imageConfig{}, fmt.Errorf("registry returned %s", res.Status)
+	}
+
+	var cfg imageConfig
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return imageConfig{}, fmt.Errorf("decoding image config: %v", err)
+	}
+	return cfg, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	b, err := ToIndentJSON(v)
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
+
+// apiRegistry resolves the {registry} path variable, writing a 404 JSON
+// error and returning nil if it doesn't name a configured registry.
+func apiRegistry(w http.ResponseWriter, r *http.Request) *Registry {
+	reg := RegistryByName(mux.Vars(r)["registry"])
+	if reg == nil {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("unknown registry"))
+		return nil
+	}
+	return reg
+}
+
+func apiCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	reg := apiRegistry(w, r)
+	if reg == nil {
+		return
+	}
+	writeJSON(w, reg.GetCatalog())
+}
+
+func apiTagsHandler(w http.ResponseWriter, r *http.Request) {
+	reg := apiRegistry(w, r)
+	if reg == nil {
+		return
+	}
+	writeJSON(w, reg.GetTags(mux.Vars(r)["name"]))
+}
+
+func apiManifestHandler(w http.ResponseWriter, r *http.Request) {
+	reg := apiRegistry(w, r)
+	if reg == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	info, err := reg.Manifest(vars["name"], vars["ref"])
+	if err != nil {
+		log.Printf("api manifest handler: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, info)
+}
+
+func apiDeleteTagHandler(w http.ResponseWriter, r *http.Request) {
+	reg := apiRegistry(w, r)
+	if reg == nil {
+		return
+	}
+
+	vars := mux.Vars(r)
+	if err := reg.DeleteTag(vars["name"], vars["tag"]); err != nil {
+		log.Printf("api delete tag handler: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,59 @@
+// Package auth manages the UI's local user accounts and authenticates
+// outgoing requests against the Docker Registry v2 API.
+package auth
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+)
+
+// Configuration is the account-management store, persisted to the file
+// passed to ReadConfig.
+type Configuration struct {
+	Users map[string]string `json:"users"`
+	path  string
+}
+
+// Config holds the accounts loaded by ReadConfig. It is read directly by
+// the users view.
+var Config Configuration
+
+// ReadConfig loads the account-management store from path, creating an
+// empty one if the file doesn't exist yet.
+func ReadConfig(path string) {
+	Config = Configuration{Users: make(map[string]string), path: path}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	if err := json.Unmarshal(b, &Config); err != nil {
+		log.Fatalf("parsing account config %s: %v", path, err)
+	}
+	Config.path = path
+}
+
+func (c *Configuration) save() error {
+	b, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, b, 0644)
+}
+
+// AddUser creates or updates a user's password and persists the store.
+func AddUser(username, password string) error {
+	if Config.Users == nil {
+		Config.Users = make(map[string]string)
+	}
+	Config.Users[username] = password
+	return Config.save()
+}
+
+// DeleteUser removes a user and persists the store.
+func DeleteUser(username string) error {
+	delete(Config.Users, username)
+	return Config.save()
+}
@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transport is an http.RoundTripper that authenticates against a Docker
+// Registry v2 API. It parses the `WWW-Authenticate` challenge returned on
+// a 401, fetches a token from the declared auth realm (or falls back to
+// HTTP Basic), and caches tokens per scope so concurrent requests share
+// them instead of re-authenticating every time.
+type Transport struct {
+	// Base is the underlying transport used for both the registry request
+	// and the token request. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+	// Username and Password are used both as HTTP Basic credentials and
+	// to authenticate the bearer token request, per docker registry auth.
+	Username string
+	Password string
+
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+}
+
+type cachedToken struct {
+	token  string
+	expiry time.Time
+}
+
+// NewTransport returns a Transport wrapping base (http.DefaultTransport if
+// nil) that authenticates with username/password when the registry
+// challenges a request.
+func NewTransport(base http.RoundTripper, username, password string) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		Base:     base,
+		Username: username,
+		Password: password,
+		tokens:   make(map[string]cachedToken),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	scope := scopeFor(req)
+
+	attempt := cloneRequest(req)
+	if scope != "" {
+		if token, ok := t.cachedToken(scope); ok {
+			attempt.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	res, err := t.Base.RoundTrip(attempt)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusUnauthorized {
+		return res, nil
+	}
+
+	challenge := res.Header.Get("Www-Authenticate")
+	if challenge == "" {
+		return res, nil
+	}
+	res.Body.Close()
+
+	retry := cloneRequest(req)
+	switch {
+	case strings.HasPrefix(challenge, "Bearer "):
+		token, expiry, err := t.bearerToken(challenge)
+		if err != nil {
+			return nil, fmt.Errorf("bearer auth: %v", err)
+		}
+		if scope != "" {
+			t.cacheToken(scope, token, expiry)
+		}
+		retry.Header.Set("Authorization", "Bearer "+token)
+	case strings.HasPrefix(challenge, "Basic "):
+		retry.SetBasicAuth(t.Username, t.Password)
+	default:
+		return res, fmt.Errorf("unsupported auth challenge: %s", challenge)
+	}
+
+	return t.Base.RoundTrip(retry)
+}
+
+// bearerToken requests a token from the realm named in a Bearer challenge,
+// authenticating with Basic credentials when they're set.
+func (t *Transport) bearerToken(challenge string) (string, time.Time, error) {
+	params := parseChallenge(challenge)
+
+	realm := params["realm"]
+	if realm == "" {
+		return "", time.Time{}, fmt.Errorf("challenge has no realm: %s", challenge)
+	}
+
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("parsing realm %s: %v", realm, err)
+	}
+
+	q := u.Query()
+	if service := params["service"]; service != "" {
+		q.Set("service", service)
+	}
+	if scope := params["scope"]; scope != "" {
+		q.Set("scope", scope)
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if t.Username != "" {
+		req.SetBasicAuth(t.Username, t.Password)
+	}
+
+	res, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("requesting token from %s: %v", realm, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("token endpoint %s returned %s", realm, res.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return "", time.Time{}, fmt.Errorf("decoding token response from %s: %v", realm, err)
+	}
+
+	token := body.Token
+	if token == "" {
+		token = body.AccessToken
+	}
+	if token == "" {
+		return "", time.Time{}, fmt.Errorf("token endpoint %s returned no token", realm)
+	}
+
+	expiresIn := body.ExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = 300
+	}
+	return token, time.Now().Add(time.Duration(expiresIn) * time.Second), nil
+}
+
+// Authorization returns the Authorization header value cached for scope
+// (as produced by scopeFor), if a token has already been obtained for it
+// by an earlier request through this Transport. It never triggers
+// authentication itself, so callers needing to hand the header to a third
+// party (e.g. Clair fetching a blob on registry-ui's behalf) should only
+// rely on it after making an authenticated request in the same scope.
+func (t *Transport) Authorization(scope string) (string, bool) {
+	token, ok := t.cachedToken(scope)
+	if !ok {
+		return "", false
+	}
+	return "Bearer " + token, true
+}
+
+func (t *Transport) cachedToken(scope string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c, ok := t.tokens[scope]
+	if !ok || time.Now().After(c.expiry) {
+		return "", false
+	}
+	return c.token, true
+}
+
+func (t *Transport) cacheToken(scope, token string, expiry time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokens[scope] = cachedToken{token: token, expiry: expiry}
+}
+
+// parseChallenge splits a `Bearer realm="...",service="...",scope="..."`
+// challenge into its key/value parameters.
+func parseChallenge(challenge string) map[string]string {
+	challenge = strings.TrimPrefix(challenge, "Bearer ")
+	// Rejoin pieces split inside a quoted value (e.g. scope="...:pull,push").
+	var parts []string
+	for _, part := range strings.Split(challenge, ",") {
+		if n := len(parts); n > 0 && strings.Count(parts[n-1], `"`)%2 == 1 {
+			parts[n-1] += "," + part
+			continue
+		}
+		parts = append(parts, part)
+	}
+
+	params := make(map[string]string)
+	for _, part := range parts {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		params[kv[0]] = strings.Trim(kv[1], `"`)
+	}
+	return params
+}
+
+// scopeFor derives the registry auth scope for req from its path, so a
+// token can be attached proactively and shared across requests to the same
+// repository instead of re-authenticating on every call.
+func scopeFor(req *http.Request) string {
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	if path == req.URL.Path {
+		return ""
+	}
+
+	if strings.HasSuffix(path, "_catalog") {
+		return "registry:catalog:*"
+	}
+
+	var name string
+	for _, marker := range []string{"/manifests/", "/tags/list", "/blobs/"} {
+		if i := strings.Index(path, marker); i >= 0 {
+			name = path[:i]
+			break
+		}
+	}
+	if name == "" {
+		return ""
+	}
+
+	action := "pull"
+	switch req.Method {
+	case http.MethodDelete, http.MethodPut, http.MethodPost, http.MethodPatch:
+		action = "pull,push"
+	}
+	return fmt.Sprintf("repository:%s:%s", name, action)
+}
+
+// CredentialsFromDockerConfig reads a docker `config.json`-style file and
+// returns the basic-auth credentials stored for registryHost, if any.
+func CredentialsFromDockerConfig(path, registryHost string) (username, password string, err error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("reading docker config %s: %v", path, err)
+	}
+
+	var cfg struct {
+		Auths map[string]struct {
+			Auth string `json:"auth"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return "", "", fmt.Errorf("parsing docker config %s: %v", path, err)
+	}
+
+	entry, ok := cfg.Auths[registryHost]
+	if !ok {
+		return "", "", fmt.Errorf("no credentials for %s in %s", registryHost, path)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+	if err != nil {
+		return "", "", fmt.Errorf("decoding auth entry for %s: %v", registryHost, err)
+	}
+
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("malformed auth entry for %s", registryHost)
+	}
+	return parts[0], parts[1], nil
+}
+
+func cloneRequest(req *http.Request) *http.Request {
+	clone := new(http.Request)
+	*clone = *req
+	clone.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		clone.Header[k] = append([]string(nil), v...)
+	}
+	return clone
+}
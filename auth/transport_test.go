@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseChallenge(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge string
+		want      map[string]string
+	}{
+		{
+			"realm, service and scope",
+			`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:foo/bar:pull"`,
+			map[string]string{
+				"realm":   "https://auth.example.com/token",
+				"service": "registry.example.com",
+				"scope":   "repository:foo/bar:pull",
+			},
+		},
+		{"realm only", `Bearer realm="https://auth.example.com/token"`, map[string]string{"realm": "https://auth.example.com/token"}},
+		{"no params", "Bearer", map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseChallenge(tt.challenge); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseChallenge(%q) = %#v, want %#v", tt.challenge, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScopeFor(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   string
+	}{
+		{"catalog", http.MethodGet, "/v2/_catalog", "registry:catalog:*"},
+		{"pull manifest", http.MethodGet, "/v2/foo/bar/manifests/latest", "repository:foo/bar:pull"},
+		{"pull tags list", http.MethodGet, "/v2/foo/bar/tags/list", "repository:foo/bar:pull"},
+		{"pull blob", http.MethodGet, "/v2/foo/bar/blobs/sha256:abc", "repository:foo/bar:pull"},
+		{"delete manifest", http.MethodDelete, "/v2/foo/bar/manifests/sha256:abc", "repository:foo/bar:pull,push"},
+		{"non-v2 path", http.MethodGet, "/users", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "https://registry.example.com"+tt.path, nil)
+			if err != nil {
+				t.Fatalf("building request: %v", err)
+			}
+			if got := scopeFor(req); got != tt.want {
+				t.Errorf("scopeFor(%s %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/jgsqware/registry-ui/vuln"
+	"github.com/spf13/viper"
+)
+
+// doRequestE issues an HTTP request through r's authenticated client and
+// returns an error instead of aborting the process on failure.
+func (r *Registry) doRequestE(method, uri string) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, uri, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("building %s %s: %v", method, uri, err)
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s %s: %v", method, uri, err)
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading body: %v", err)
+	}
+
+	return res, b, nil
+}
+
+// nextLink extracts the "next" relative URI from an RFC5988 Link header, as
+// returned by the registry when a listing is paginated. It returns "" when
+// there is no further page.
+func nextLink(header string) string {
+	if header == "" {
+		return ""
+	}
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.SplitN(link, ";", 2)
+		if len(parts) != 2 || !strings.Contains(parts[1], `rel="next"`) {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+	}
+	return ""
+}
+
+// resolveURI turns the (possibly relative) URI returned in a Link header
+// into one doRequestE can use directly against r.
+func (r *Registry) resolveURI(uri string) string {
+	if uri == "" || strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://") {
+		return uri
+	}
+	return r.URI + uri
+}
+
+// GetCatalog walks r's `_catalog` endpoint page by page and fans out the
+// per-repository tag listing across a bounded worker pool. A failure on a
+// single page or repository is recorded rather than aborting the whole
+// catalog, since one bad repo shouldn't take down the UI.
+func (r *Registry) GetCatalog() catalog {
+	var c catalog
+	c.AccountMgmt = viper.GetBool("account_mgmt_enabled")
+	c.Registry = r.Name
+	c.Repositories = make(map[string][]image)
+
+	pageSize := viper.GetInt("page_size")
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+
+	var repositories []string
+	uri := fmt.Sprintf("%s/v2/_catalog?n=%d", r.URI, pageSize)
+	for uri != "" {
+		res, b, err := r.doRequestE("GET", uri)
+		if err != nil {
+			log.Printf("fetching catalog page from %s: %v", r.Name, err)
+			c.Error = err.Error()
+			break
+		}
+
+		if res.StatusCode != http.StatusOK {
+			log.Printf("fetching catalog page from %s: registry returned %s", r.Name, res.Status)
+			c.Error = fmt.Sprintf("registry returned %s", res.Status)
+			break
+		}
+
+		var d _catalog
+		if err := json.Unmarshal(b, &d); err != nil {
+			log.Printf("unmarshalling catalog page from %s: %v", r.Name, err)
+			c.Error = err.Error()
+			break
+		}
+
+		repositories = append(repositories, d.Repositories...)
+		uri = r.resolveURI(nextLink(res.Header.Get("Link")))
+	}
+
+	workers := viper.GetInt("catalog_workers")
+	if workers <= 0 {
+		workers = 10
+	}
+
+	for _, img := range r.fetchTags(repositories, workers) {
+		namespace := "-"
+		if strings.Contains(img.Name, "/") {
+			namespace = strings.SplitN(img.Name, "/", 2)[0]
+		}
+		c.Repositories[namespace] = append(c.Repositories[namespace], img)
+	}
+	return c
+}
+
+// fetchTags resolves the tags of every repository concurrently through a
+// bounded pool of workers, so a registry listing thousands of repositories
+// doesn't serialize one HTTP round-trip per repo.
+func (r *Registry) fetchTags(repositories []string, workers int) []image {
+	jobs := make(chan string)
+	results := make(chan image)
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for repo := range jobs {
+				results <- r.GetTags(repo)
+			}
+		}()
+	}
+
+	go func() {
+		for _, repo := range repositories {
+			jobs <- repo
+		}
+		close(jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	images := make([]image, 0, len(repositories))
+	for img := range results {
+		images = append(images, img)
+	}
+	return images
+}
+
+// GetTags fetches every tag of imageName from r, following pagination
+// links. On failure it returns an image carrying the Error field instead,
+// so a single unreachable repository only blanks out its own row.
+func (r *Registry) GetTags(imageName string) image {
+	i := image{Name: imageName}
+
+	pageSize := viper.GetInt("page_size")
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+
+	uri := fmt.Sprintf("%s/v2/%s/tags/list?n=%d", r.URI, imageName, pageSize)
+	for uri != "" {
+		res, b, err := r.doRequestE("GET", uri)
+		if err != nil {
+			i.Error = err.Error()
+			return i
+		}
+
+		if res.StatusCode != http.StatusOK {
+			i.Error = fmt.Sprintf("registry returned %s", res.Status)
+			return i
+		}
+
+		var page image
+		if err := json.Unmarshal(b, &page); err != nil {
+			i.Error = fmt.Sprintf("unmarshalling tags: %v", err)
+			return i
+		}
+
+		i.Tags = append(i.Tags, page.Tags...)
+		uri = r.resolveURI(nextLink(res.Header.Get("Link")))
+	}
+
+	if clairClient != nil {
+		i.Vulnerabilities = make(map[string]vuln.Summary)
+		for _, tag := range i.Tags {
+			digest, err := r.manifestDigest(imageName, tag)
+			if err != nil {
+				continue
+			}
+			if s, ok := clairClient.Summary(digest); ok {
+				i.Vulnerabilities[tag] = s
+			}
+		}
+	}
+	return i
+}
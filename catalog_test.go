@@ -0,0 +1,56 @@
+package main
+
+import "testing"
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"empty header", "", ""},
+		{
+			"single next link",
+			`</v2/_catalog?n=100&last=repo1>; rel="next"`,
+			"/v2/_catalog?n=100&last=repo1",
+		},
+		{
+			"next link among others",
+			`</v2/_catalog?n=100&last=repo0>; rel="first", </v2/_catalog?n=100&last=repo1>; rel="next"`,
+			"/v2/_catalog?n=100&last=repo1",
+		},
+		{"no rel=next present", `</v2/_catalog?n=100&last=repo0>; rel="first"`, ""},
+		{"malformed header", "not a link header", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextLink(tt.header); got != tt.want {
+				t.Errorf("nextLink(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryResolveURI(t *testing.T) {
+	r := &Registry{URI: "https://registry.example.com"}
+
+	tests := []struct {
+		name string
+		uri  string
+		want string
+	}{
+		{"empty", "", ""},
+		{"relative path", "/v2/_catalog?n=100&last=repo1", "https://registry.example.com/v2/_catalog?n=100&last=repo1"},
+		{"already absolute http", "http://other.example.com/v2/_catalog", "http://other.example.com/v2/_catalog"},
+		{"already absolute https", "https://other.example.com/v2/_catalog", "https://other.example.com/v2/_catalog"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.resolveURI(tt.uri); got != tt.want {
+				t.Errorf("resolveURI(%q) = %q, want %q", tt.uri, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// errManifestNotFound marks a deleteManifest failure caused by the manifest
+// already being gone, so callers that expect deletes to race (e.g. multiple
+// tags sharing a digest) can tell it apart from a real failure.
+var errManifestNotFound = errors.New("manifest not found")
+
+// manifestDigest resolves the content digest of repository:reference on r
+// via a HEAD request against the manifests endpoint, as required before a
+// Registry v2 delete.
+func (r *Registry) manifestDigest(repository, reference string) (string, error) {
+	req, err := http.NewRequest("HEAD", r.URI+"/v2/"+repository+"/manifests/"+reference, nil)
+	if err != nil {
+		return "", fmt.Errorf("resolving digest for %s:%s: %v", repository, reference, err)
+	}
+	req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("resolving digest for %s:%s: %v", repository, reference, err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("resolving digest for %s:%s: registry returned %s", repository, reference, res.Status)
+	}
+
+	digest := res.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("resolving digest for %s:%s: registry did not return Docker-Content-Digest", repository, reference)
+	}
+
+	return digest, nil
+}
+
+// deleteManifest removes the manifest identified by digest from repository
+// on r. Deleting a manifest also removes every tag that points at it.
+func (r *Registry) deleteManifest(repository, digest string) error {
+	res, body, err := r.doRequestE("DELETE", r.URI+"/v2/"+repository+"/manifests/"+digest)
+	if err != nil {
+		return fmt.Errorf("deleting %s@%s: %v", repository, digest, err)
+	}
+
+	switch res.StatusCode {
+	case http.StatusAccepted:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("deleting %s@%s: %w", repository, digest, errManifestNotFound)
+	case http.StatusMethodNotAllowed:
+		return fmt.Errorf("deleting %s@%s: registry does not allow deletion (enable REGISTRY_STORAGE_DELETE_ENABLED on the registry)", repository, digest)
+	default:
+		return fmt.Errorf("deleting %s@%s: registry returned %s: %s", repository, digest, res.Status, body)
+	}
+}
+
+// DeleteTag removes a single tag from repository on r by resolving the
+// manifest digest it currently points to and deleting that manifest.
+func (r *Registry) DeleteTag(repository, tag string) error {
+	digest, err := r.manifestDigest(repository, tag)
+	if err != nil {
+		return err
+	}
+	return r.deleteManifest(repository, digest)
+}
+
+// DeleteRepository removes every tag in repository on r, leaving it empty
+// in the catalog. Tags are resolved to manifest digests and deduplicated
+// before deleting, since two tags commonly alias the same digest (e.g.
+// "latest" and a version tag) and deleting one would otherwise take the
+// other with it, 404ing a later delete-by-tag for no real reason.
+func (r *Registry) DeleteRepository(repository string) error {
+	img := r.GetTags(repository)
+	if img.Error != "" {
+		return fmt.Errorf("deleting repository %s: listing tags: %s", repository, img.Error)
+	}
+
+	digests := make(map[string]bool, len(img.Tags))
+	for _, tag := range img.Tags {
+		digest, err := r.manifestDigest(repository, tag)
+		if err != nil {
+			return fmt.Errorf("deleting repository %s: %v", repository, err)
+		}
+		digests[digest] = true
+	}
+
+	for digest := range digests {
+		if err := r.deleteManifest(repository, digest); err != nil && !errors.Is(err, errManifestNotFound) {
+			return fmt.Errorf("deleting repository %s: %v", repository, err)
+		}
+	}
+	return nil
+}
@@ -1,19 +1,17 @@
 package main
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
-	"strings"
 	"text/template"
 
 	"github.com/gorilla/mux"
 	"github.com/jgsqware/registry-ui/auth"
+	"github.com/jgsqware/registry-ui/vuln"
 	"github.com/spf13/viper"
 )
 
@@ -27,16 +25,17 @@ Repositories [{{.Repositories | len}}]:
 	{{end}}
 `
 
-var registryURI string
-
 type image struct {
-	Name string   `json:"name"`
-	Tags []string `json:"tags"`
+	Name            string                  `json:"name"`
+	Tags            []string                `json:"tags"`
+	Error           string                  `json:"-"`
+	Vulnerabilities map[string]vuln.Summary `json:"vulnerabilities,omitempty"`
 }
 
 type catalog struct {
 	AccountMgmt  bool
 	Registry     string
+	Error        string `json:"-"`
 	Repositories map[string][]image
 }
 
@@ -68,25 +67,54 @@ func renderTemplate(w http.ResponseWriter, tmpl string, p interface{}) error {
 	return nil
 }
 
-func loadPage(p string) interface{} {
-	switch p {
-	case "catalog":
-		return GetCatalog()
-	case "notfound":
-		return "notfound"
-	default:
-		return nil
+func registriesHandler(w http.ResponseWriter, r *http.Request) {
+	err := renderTemplate(w, "registries", RegistryNames())
+	if err != nil {
+		log.Printf("registries handler: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
 func catalogHandler(w http.ResponseWriter, r *http.Request) {
-	err := renderTemplate(w, "catalog", GetCatalog())
+	reg := RegistryByName(mux.Vars(r)["registry"])
+	if reg == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	err := renderTemplate(w, "catalog", reg.GetCatalog())
 	if err != nil {
 		log.Printf("catalog handler: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
+func catalogActionHandler(w http.ResponseWriter, r *http.Request) {
+	reg := RegistryByName(mux.Vars(r)["registry"])
+	if reg == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	repository := r.FormValue("repository")
+	switch r.FormValue("method") {
+	case "delete-tag":
+		tag := r.FormValue("tag")
+		if err := reg.DeleteTag(repository, tag); err != nil {
+			log.Printf("catalog action handler: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case "delete-repo":
+		if err := reg.DeleteRepository(repository); err != nil {
+			log.Printf("catalog action handler: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	http.Redirect(w, r, "/catalog/"+reg.Name, http.StatusFound)
+}
+
 func usersHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
@@ -118,11 +146,16 @@ func main() {
 
 	viper.SetEnvPrefix("registryui")
 	viper.SetDefault("port", 8080)
+	viper.SetDefault("page_size", 100)
+	viper.SetDefault("catalog_workers", 10)
 	viper.AutomaticEnv()
 
-	registryURI = viper.GetString("hub_uri")
-	if registryURI == "" {
-		log.Fatalln("no registry uri provided")
+	registriesConfig := viper.GetString("registries_config")
+	if registriesConfig == "" {
+		log.Fatalln("no registries config provided")
+	}
+	if err := LoadRegistries(registriesConfig); err != nil {
+		log.Fatalf("loading registries: %v", err)
 	}
 
 	if viper.GetBool("account_mgmt_enabled") {
@@ -132,16 +165,31 @@ func main() {
 		auth.ReadConfig(viper.GetString("account_mgmt_config"))
 	}
 
-	http.DefaultClient.Transport = &http.Transport{
-		TLSClientConfig:    &tls.Config{InsecureSkipVerify: true},
-		DisableCompression: true,
+	// registryui_clair_url must be a Clair v1 endpoint; see vuln package doc.
+	if clairURL := viper.GetString("clair_url"); clairURL != "" {
+		clairClient = vuln.NewClient(clairURL, viper.GetBool("clair_insecure"))
 	}
 
 	var isCmd = flag.Bool("sout", false, "Display registry in stdout")
+	var regName = flag.String("registry", "", "Registry to display with -sout (default: first configured)")
 	flag.Parse()
 
 	if *isCmd == true {
-		c := GetCatalog()
+		name := *regName
+		if name == "" {
+			names := RegistryNames()
+			if len(names) == 0 {
+				log.Fatalln("no registries configured")
+			}
+			name = names[0]
+		}
+
+		reg := RegistryByName(name)
+		if reg == nil {
+			log.Fatalf("unknown registry %s", name)
+		}
+
+		c := reg.GetCatalog()
 		err := template.Must(template.New("catalog").Parse(catalogTplt)).Execute(os.Stdout, c)
 		if err != nil {
 			log.Fatalf("rendering : %v", err)
@@ -152,7 +200,15 @@ func main() {
 	log.Printf("Starting Server on %s\n", s)
 
 	router := mux.NewRouter()
-	router.Path("/catalog").HandlerFunc(catalogHandler).Methods("GET")
+	router.Path("/api/v1/{registry}/catalog").HandlerFunc(apiCatalogHandler).Methods("GET")
+	router.Path("/api/v1/{registry}/repositories/{name:.*}/tags").HandlerFunc(apiTagsHandler).Methods("GET")
+	router.Path("/api/v1/{registry}/repositories/{name:.*}/manifests/{ref}").HandlerFunc(apiManifestHandler).Methods("GET")
+	router.Path("/api/v1/{registry}/repositories/{name:.*}/tags/{tag}").HandlerFunc(apiDeleteTagHandler).Methods("DELETE")
+	router.Path("/registries").HandlerFunc(registriesHandler).Methods("GET")
+	router.Path("/catalog/{registry}").HandlerFunc(catalogHandler).Methods("GET")
+	router.Path("/catalog/{registry}").HandlerFunc(catalogActionHandler).Methods("POST")
+	router.Path("/scan/{registry}/{repo:.*}/{tag}").HandlerFunc(scanHandler).Methods("POST")
+	router.Path("/scan-all/{registry}").HandlerFunc(scanAllHandler).Methods("POST")
 	router.Path("/users").HandlerFunc(usersHandler).Methods("GET", "POST")
 	http.Handle("/", router)
 	http.ListenAndServe(s, nil)
@@ -166,66 +222,3 @@ func ToIndentJSON(v interface{}) ([]byte, error) {
 	}
 	return b, nil
 }
-
-func doRequest(method string, uri string) []byte {
-	req, err := http.NewRequest(method, uri, nil)
-	res, err := http.DefaultClient.Do(req)
-
-	if err != nil {
-		log.Fatalf("retrieving catalog: %v", err)
-	}
-
-	if res.StatusCode == http.StatusUnauthorized {
-		err := auth.Authenticate(res, req)
-
-		if err != nil {
-			log.Fatalf("authenticating: %v", err)
-		}
-
-		res, err = http.DefaultClient.Do(req)
-		if err != nil {
-			log.Fatalf("retrieving catalog: %v", err)
-		}
-
-	}
-
-	b, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		log.Fatalf("reading body: %v", err)
-	}
-
-	return b
-}
-
-func GetCatalog() catalog {
-	var d _catalog
-	b := doRequest("GET", "http://"+registryURI+"/v2/_catalog")
-	if err := json.Unmarshal(b, &d); err != nil {
-		log.Fatalf("marshalling result: err")
-	}
-
-	var c catalog
-	c.AccountMgmt = viper.GetBool("account_mgmt_enabled")
-	c.Registry = registryURI
-	c.Repositories = make(map[string][]image)
-	for _, repository := range d.Repositories {
-		if strings.Contains(repository, "/") {
-			r := strings.Split(repository, "/")
-			c.Repositories[r[0]] = append(c.Repositories[r[0]], GetTags(repository))
-		} else {
-			c.Repositories["-"] = append(c.Repositories["-"], GetTags(repository))
-		}
-	}
-	return c
-}
-
-func GetTags(imageName string) image {
-	var i image
-	b := doRequest("GET", "http://"+registryURI+"/v2/"+imageName+"/tags/list")
-
-	if err := json.Unmarshal(b, &i); err != nil {
-		log.Fatalf("marshalling result: err")
-	}
-	return i
-
-}
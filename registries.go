@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/jgsqware/registry-ui/auth"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Registry is one registry endpoint this instance fronts, as declared in
+// the file pointed to by registryui_registries_config.
+type Registry struct {
+	Name             string `yaml:"name"`
+	URI              string `yaml:"uri"`
+	Username         string `yaml:"username"`
+	Password         string `yaml:"password"`
+	DockerConfig     string `yaml:"docker_config"`
+	VerifyRemoteCert *bool  `yaml:"verify_remote_cert"`
+
+	client    *http.Client
+	transport *auth.Transport
+}
+
+// verifyRemoteCert reports whether r should verify the remote registry's
+// TLS certificate: the entry's verify_remote_cert field, or true when the
+// entry omits it. No process-wide setting is consulted.
+func (r *Registry) verifyRemoteCert() bool {
+	return r.VerifyRemoteCert == nil || *r.VerifyRemoteCert
+}
+
+// pullHeaders returns the Authorization header Clair should send when it
+// pulls repository's blobs from r on registry-ui's behalf, using whatever
+// credentials r.transport already has cached for a pull of repository, or
+// r's static Basic credentials as a fallback. Returns nil if r has neither.
+func (r *Registry) pullHeaders(repository string) map[string]string {
+	if v, ok := r.transport.Authorization("repository:" + repository + ":pull"); ok {
+		return map[string]string{"Authorization": v}
+	}
+	if r.Username != "" {
+		basic := base64.StdEncoding.EncodeToString([]byte(r.Username + ":" + r.Password))
+		return map[string]string{"Authorization": "Basic " + basic}
+	}
+	return nil
+}
+
+type registriesConfig struct {
+	Registries []*Registry `yaml:"registries"`
+}
+
+var (
+	registriesMu  sync.RWMutex
+	registries    map[string]*Registry
+	registryOrder []string
+)
+
+// LoadRegistries reads the YAML registries config at path, building an
+// authenticated HTTP client per registry, and replaces the active set.
+func LoadRegistries(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading registries config %s: %v", path, err)
+	}
+
+	var cfg registriesConfig
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return fmt.Errorf("parsing registries config %s: %v", path, err)
+	}
+
+	m := make(map[string]*Registry, len(cfg.Registries))
+	order := make([]string, 0, len(cfg.Registries))
+	for _, r := range cfg.Registries {
+		if r.Name == "" {
+			return fmt.Errorf("registries config %s: a registry is missing a name", path)
+		}
+		r.URI = strings.TrimRight(r.URI, "/")
+
+		if r.Username == "" && r.DockerConfig != "" {
+			username, password, err := auth.CredentialsFromDockerConfig(r.DockerConfig, registryHost(r.URI))
+			if err != nil {
+				return fmt.Errorf("registry %s: %v", r.Name, err)
+			}
+			r.Username, r.Password = username, password
+		}
+
+		r.transport = auth.NewTransport(&http.Transport{
+			TLSClientConfig:    &tls.Config{InsecureSkipVerify: !r.verifyRemoteCert()},
+			DisableCompression: true,
+		}, r.Username, r.Password)
+		r.client = &http.Client{Transport: r.transport}
+		m[r.Name] = r
+		order = append(order, r.Name)
+	}
+
+	registriesMu.Lock()
+	registries, registryOrder = m, order
+	registriesMu.Unlock()
+	return nil
+}
+
+// registryHost extracts the host docker config.json keys its auths by from
+// a registry URI, falling back to the URI itself if it has no scheme.
+func registryHost(uri string) string {
+	if u, err := url.Parse(uri); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return uri
+}
+
+// RegistryByName returns the configured registry named name, or nil if no
+// such registry was declared.
+func RegistryByName(name string) *Registry {
+	registriesMu.RLock()
+	defer registriesMu.RUnlock()
+	return registries[name]
+}
+
+// RegistryNames returns every configured registry's name, in config order.
+func RegistryNames() []string {
+	registriesMu.RLock()
+	defer registriesMu.RUnlock()
+	return append([]string(nil), registryOrder...)
+}
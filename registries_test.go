@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestRegistryHost(t *testing.T) {
+	tests := []struct {
+		name string
+		uri  string
+		want string
+	}{
+		{"https uri", "https://registry.example.com:5000", "registry.example.com:5000"},
+		{"http uri", "http://registry.example.com", "registry.example.com"},
+		{"bare host, no scheme", "registry.example.com", "registry.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := registryHost(tt.uri); got != tt.want {
+				t.Errorf("registryHost(%q) = %q, want %q", tt.uri, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryVerifyRemoteCert(t *testing.T) {
+	yes, no := true, false
+
+	tests := []struct {
+		name string
+		r    Registry
+		want bool
+	}{
+		{"unset defaults to true", Registry{}, true},
+		{"explicit true", Registry{VerifyRemoteCert: &yes}, true},
+		{"explicit false", Registry{VerifyRemoteCert: &no}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.verifyRemoteCert(); got != tt.want {
+				t.Errorf("verifyRemoteCert() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRegistries(t *testing.T) {
+	f, err := ioutil.TempFile("", "registries-*.yaml")
+	if err != nil {
+		t.Fatalf("creating temp config: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	const cfg = `
+registries:
+  - name: staging
+    uri: https://staging.example.com
+  - name: prod
+    uri: https://prod.example.com/
+    verify_remote_cert: false
+`
+	if _, err := f.WriteString(cfg); err != nil {
+		t.Fatalf("writing temp config: %v", err)
+	}
+	f.Close()
+
+	if err := LoadRegistries(f.Name()); err != nil {
+		t.Fatalf("LoadRegistries: %v", err)
+	}
+
+	if got, want := RegistryNames(), []string{"staging", "prod"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RegistryNames() = %v, want %v", got, want)
+	}
+
+	staging := RegistryByName("staging")
+	if staging == nil {
+		t.Fatal("RegistryByName(\"staging\") = nil")
+	}
+	if !staging.verifyRemoteCert() {
+		t.Error("staging.verifyRemoteCert() = false, want true (default)")
+	}
+
+	prod := RegistryByName("prod")
+	if prod == nil {
+		t.Fatal("RegistryByName(\"prod\") = nil")
+	}
+	if prod.URI != "https://prod.example.com" {
+		t.Errorf("prod.URI = %q, want trailing slash trimmed", prod.URI)
+	}
+	if prod.verifyRemoteCert() {
+		t.Error("prod.verifyRemoteCert() = true, want false (explicit)")
+	}
+
+	if RegistryByName("missing") != nil {
+		t.Error(`RegistryByName("missing") != nil`)
+	}
+}
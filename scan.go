@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/jgsqware/registry-ui/vuln"
+)
+
+// clairClient is nil unless registryui_clair_url is configured, in which
+// case vulnerability scanning endpoints are enabled.
+var clairClient *vuln.Client
+
+// manifestLayers resolves the manifest digest and its layer chain,
+// base-first, for repository:reference on r. It understands both the v2
+// and legacy v1 (schema 1) manifest formats.
+func (r *Registry) manifestLayers(repository, reference string) (string, []string, error) {
+	req, err := http.NewRequest("GET", r.URI+"/v2/"+repository+"/manifests/"+reference, nil)
+	if err != nil {
+		return "", nil, fmt.Errorf("building manifest request for %s:%s: %v", repository, reference, err)
+	}
+	req.Header.Set("Accept", "application/vnd.docker.distribution.manifest.v2+json")
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("fetching manifest %s:%s: %v", repository, reference, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("fetching manifest %s:%s: registry returned %s", repository, reference, res.Status)
+	}
+
+	digest := res.Header.Get("Docker-Content-Digest")
+
+	var m struct {
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+		FSLayers []struct {
+			BlobSum string `json:"blobSum"`
+		} `json:"fsLayers"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&m); err != nil {
+		return "", nil, fmt.Errorf("decoding manifest %s:%s: %v", repository, reference, err)
+	}
+
+	if len(m.Layers) > 0 {
+		layers := make([]string, len(m.Layers))
+		for i, l := range m.Layers {
+			layers[i] = l.Digest
+		}
+		return digest, layers, nil
+	}
+
+	// Schema 1 lists layers parent-last; reverse to base-first for Clair's chain.
+	layers := make([]string, len(m.FSLayers))
+	for i, l := range m.FSLayers {
+		layers[len(m.FSLayers)-1-i] = l.BlobSum
+	}
+	return digest, layers, nil
+}
+
+func scanHandler(w http.ResponseWriter, req *http.Request) {
+	if clairClient == nil {
+		http.Error(w, "vulnerability scanning is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	vars := mux.Vars(req)
+	reg := RegistryByName(vars["registry"])
+	if reg == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	repository, tag := vars["repo"], vars["tag"]
+
+	digest, layers, err := reg.manifestLayers(repository, tag)
+	if err != nil {
+		log.Printf("scan handler: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := clairClient.Scan(reg.URI, repository, digest, layers, reg.pullHeaders(repository)); err != nil {
+		log.Printf("scan handler: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, req, "/catalog/"+reg.Name, http.StatusFound)
+}
+
+func scanAllHandler(w http.ResponseWriter, req *http.Request) {
+	if clairClient == nil {
+		http.Error(w, "vulnerability scanning is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	reg := RegistryByName(mux.Vars(req)["registry"])
+	if reg == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	c := reg.GetCatalog()
+	for _, images := range c.Repositories {
+		for _, img := range images {
+			for _, tag := range img.Tags {
+				digest, layers, err := reg.manifestLayers(img.Name, tag)
+				if err != nil {
+					log.Printf("scan-all %s: %s:%s: %v", reg.Name, img.Name, tag, err)
+					continue
+				}
+				if _, err := clairClient.Scan(reg.URI, img.Name, digest, layers, reg.pullHeaders(img.Name)); err != nil {
+					log.Printf("scan-all %s: %s:%s: %v", reg.Name, img.Name, tag, err)
+				}
+			}
+		}
+	}
+
+	http.Redirect(w, req, "/catalog/"+reg.Name, http.StatusFound)
+}
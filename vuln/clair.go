@@ -0,0 +1,99 @@
+package vuln
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// layerRequest is the body Clair v1 expects at POST /v1/layers. Path must
+// be a URL Clair itself can fetch the layer blob from; registry-ui passes
+// the registry's own blob endpoint. Headers are sent by Clair along with
+// that fetch, so a private registry's pull credentials travel with it.
+type layerRequest struct {
+	Layer struct {
+		Name       string            `json:"Name"`
+		Path       string            `json:"Path"`
+		ParentName string            `json:"ParentName,omitempty"`
+		Format     string            `json:"Format"`
+		Headers    map[string]string `json:"Headers,omitempty"`
+	} `json:"Layer"`
+}
+
+// layerResponse is the subset of Clair's GET /v1/layers/{name}?vulnerabilities
+// response this package cares about.
+type layerResponse struct {
+	Layer struct {
+		Features []struct {
+			Vulnerabilities []struct {
+				Severity string `json:"Severity"`
+			} `json:"Vulnerabilities"`
+		} `json:"Features"`
+	} `json:"Layer"`
+}
+
+func (c *Client) postLayer(digest, blobURL, parent string, headers map[string]string) error {
+	var req layerRequest
+	req.Layer.Name = digest
+	req.Layer.Path = blobURL
+	req.Layer.ParentName = parent
+	req.Layer.Format = "Docker"
+	req.Layer.Headers = headers
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("encoding layer %s: %v", digest, err)
+	}
+
+	res, err := c.http.Post(c.baseURL+"/v1/layers", "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("pushing layer %s to clair: %v", digest, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusCreated && res.StatusCode != http.StatusOK {
+		return fmt.Errorf("pushing layer %s to clair: clair returned %s", digest, res.Status)
+	}
+	return nil
+}
+
+func (c *Client) vulnerabilities(lastLayer string) (Summary, error) {
+	res, err := c.http.Get(c.baseURL + "/v1/layers/" + lastLayer + "?vulnerabilities")
+	if err != nil {
+		return Summary{}, fmt.Errorf("fetching clair report for %s: %v", lastLayer, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Summary{}, fmt.Errorf("fetching clair report for %s: clair returned %s", lastLayer, res.Status)
+	}
+
+	var lr layerResponse
+	if err := json.NewDecoder(res.Body).Decode(&lr); err != nil {
+		return Summary{}, fmt.Errorf("decoding clair report for %s: %v", lastLayer, err)
+	}
+
+	var s Summary
+	for _, feature := range lr.Layer.Features {
+		for _, v := range feature.Vulnerabilities {
+			switch v.Severity {
+			case "Negligible":
+				s.Negligible++
+			case "Low":
+				s.Low++
+			case "Medium":
+				s.Medium++
+			case "High":
+				s.High++
+			case "Critical":
+				s.Critical++
+			case "Defcon1":
+				s.Defcon1++
+			default:
+				s.Unknown++
+			}
+		}
+	}
+	return s, nil
+}
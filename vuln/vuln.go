@@ -0,0 +1,92 @@
+// Package vuln scans registry images for known vulnerabilities using a
+// Clair (https://github.com/quay/clair) v1 instance and caches the
+// aggregated result per manifest digest.
+//
+// Only the Clair v1 (/v1/layers) API is implemented. Clair v3 replaced it
+// with a gRPC/ancestry-based API that this package does not speak;
+// registryui_clair_url must point at a v1-compatible endpoint.
+package vuln
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Summary aggregates a manifest's vulnerabilities by severity, using the
+// bucket names Clair reports.
+type Summary struct {
+	Unknown    int `json:"unknown"`
+	Negligible int `json:"negligible"`
+	Low        int `json:"low"`
+	Medium     int `json:"medium"`
+	High       int `json:"high"`
+	Critical   int `json:"critical"`
+	Defcon1    int `json:"defcon1"`
+}
+
+// Client talks to a Clair v1 API and caches the result of each scanned
+// manifest by digest, so rendering the catalog doesn't re-trigger a scan.
+type Client struct {
+	baseURL string
+	http    *http.Client
+
+	mu    sync.RWMutex
+	cache map[string]Summary
+}
+
+// NewClient returns a Client targeting the Clair instance at url. insecure
+// disables TLS certificate verification, mirroring registryui_clair_insecure.
+func NewClient(url string, insecure bool) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(url, "/"),
+		http: &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure}},
+		},
+		cache: make(map[string]Summary),
+	}
+}
+
+// Summary returns the cached report for digest, if a scan has already
+// completed for it. It never triggers a scan itself.
+func (c *Client) Summary(digest string) (Summary, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	s, ok := c.cache[digest]
+	return s, ok
+}
+
+// Scan pushes every layer of the image, base-first, to Clair so it can
+// resolve features through the parent chain, then fetches and caches the
+// aggregated vulnerability report for digest. Clair fetches each layer
+// itself from registryURI/v2/repository/blobs/<layer>, sending headers
+// (typically the Authorization a private registry requires) along with
+// that request.
+func (c *Client) Scan(registryURI, repository, digest string, layers []string, headers map[string]string) (Summary, error) {
+	if len(layers) == 0 {
+		return Summary{}, fmt.Errorf("scanning %s: no layers to push", digest)
+	}
+
+	registryURI = strings.TrimRight(registryURI, "/")
+
+	var parent string
+	for _, layer := range layers {
+		blobURL := registryURI + "/v2/" + repository + "/blobs/" + layer
+		if err := c.postLayer(layer, blobURL, parent, headers); err != nil {
+			return Summary{}, err
+		}
+		parent = layer
+	}
+
+	s, err := c.vulnerabilities(layers[len(layers)-1])
+	if err != nil {
+		return Summary{}, err
+	}
+
+	c.mu.Lock()
+	c.cache[digest] = s
+	c.mu.Unlock()
+	return s, nil
+}